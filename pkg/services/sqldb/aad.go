@@ -0,0 +1,78 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	az "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/open-service-broker-azure/pkg/azure"
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+)
+
+// aadAdmin deploys a Microsoft.Sql/servers/administrators child resource
+// of kind ActiveDirectory for a newly created server. Existing servers
+// configure their Azure AD admin out of band, so this step is a no-op
+// for the existing-server scenario.
+func (s *serviceManager) aadAdmin(
+	_ context.Context,
+	instance service.Instance,
+	_ service.Plan,
+) (service.ProvisioningContext, error) {
+	pc, ok := instance.ProvisioningContext.(*mssqlProvisioningContext)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.ProvisioningContext as *mssqlProvisioningContext",
+		)
+	}
+	pp, ok := instance.ProvisioningParameters.(*ProvisioningParameters)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.ProvisioningParameters as " +
+				"*mssql.ProvisioningParameters",
+		)
+	}
+	if !pc.IsNewServer || pp.ActiveDirectoryAdmin == nil {
+		return pc, nil
+	}
+
+	if _, err := s.armDeployer.Deploy(
+		pc.ARMDeploymentName+"-aad-admin",
+		instance.StandardProvisioningContext.ResourceGroup,
+		instance.StandardProvisioningContext.Location,
+		armTemplateAADAdminBytes,
+		nil, // Go template params
+		map[string]interface{}{
+			"serverName": pc.ServerName,
+			"login":      pp.ActiveDirectoryAdmin.Login,
+			"objectId":   pp.ActiveDirectoryAdmin.ObjectID,
+			"tenantId":   pp.ActiveDirectoryAdmin.TenantID,
+		},
+		instance.StandardProvisioningContext.Tags,
+	); err != nil {
+		return nil, fmt.Errorf(
+			"error deploying Azure AD administrator for server: %s",
+			err,
+		)
+	}
+
+	return pc, nil
+}
+
+// aadAuthorityURL returns the Azure AD authority a client should use to
+// acquire an access token for the given tenant, honoring the broker's
+// configured Azure environment (e.g. Azure Government/China/Germany)
+// rather than assuming the public cloud.
+func aadAuthorityURL(tenantID string) (string, error) {
+	azureConfig, err := azure.GetConfig()
+	if err != nil {
+		return "", err
+	}
+	azureEnvironment, err := az.EnvironmentFromName(azureConfig.Environment)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(azureEnvironment.ActiveDirectoryEndpoint, "/") +
+		"/" + tenantID, nil
+}
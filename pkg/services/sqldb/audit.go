@@ -0,0 +1,103 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+)
+
+// defaultAuditActionsAndGroups is the Azure-recommended baseline applied
+// when the caller enables auditing without specifying any action groups.
+var defaultAuditActionsAndGroups = []string{
+	"SUCCESSFUL_DATABASE_AUTHENTICATION_GROUP",
+	"FAILED_DATABASE_AUTHENTICATION_GROUP",
+	"BATCH_COMPLETED_GROUP",
+}
+
+// configureAuditingAndThreatDetection PUTs the database's auditingSettings
+// and securityAlertPolicies child resources when the caller opted into
+// either feature. It is a no-op when neither is enabled.
+func (s *serviceManager) configureAuditingAndThreatDetection(
+	_ context.Context,
+	instance service.Instance,
+	_ service.Plan,
+) (service.ProvisioningContext, error) {
+	pc, ok := instance.ProvisioningContext.(*mssqlProvisioningContext)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.ProvisioningContext as *mssqlProvisioningContext",
+		)
+	}
+	pp, ok := instance.ProvisioningParameters.(*ProvisioningParameters)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.ProvisioningParameters as " +
+				"*mssql.ProvisioningParameters",
+		)
+	}
+	if !pp.Auditing.Enabled && !pp.ThreatDetection.Enabled {
+		return pc, nil
+	}
+
+	resourceGroup := instance.StandardProvisioningContext.ResourceGroup
+	location := instance.StandardProvisioningContext.Location
+	if !pc.IsNewServer {
+		server, ok := s.mssqlConfig.Servers[pc.ServerName]
+		if !ok {
+			return nil, fmt.Errorf(
+				`can't find serverName "%s" in Azure SQL Server configuration`,
+				pc.ServerName,
+			)
+		}
+		resourceGroup = server.ResourceGroupName
+		location = server.Location
+	}
+
+	armTemplateParameters := map[string]interface{}{
+		"serverName":   pc.ServerName,
+		"databaseName": pc.DatabaseName,
+	}
+	if pp.Auditing.Enabled {
+		actionsAndGroups := pp.Auditing.AuditActionsAndGroups
+		if len(actionsAndGroups) == 0 {
+			actionsAndGroups = defaultAuditActionsAndGroups
+		}
+		armTemplateParameters["auditingEnabled"] = true
+		armTemplateParameters["auditStorageEndpoint"] = pp.Auditing.StorageEndpoint
+		armTemplateParameters["auditStorageAccountAccessKey"] = pp.Auditing.
+			StorageAccountAccessKey
+		armTemplateParameters["auditUseServerIdentity"] = pp.Auditing.UseServerIdentity
+		armTemplateParameters["auditRetentionDays"] = pp.Auditing.RetentionDays
+		armTemplateParameters["auditActionsAndGroups"] = actionsAndGroups
+	}
+	if pp.ThreatDetection.Enabled {
+		armTemplateParameters["threatDetectionEnabled"] = true
+		armTemplateParameters["threatDetectionEmailAddresses"] = pp.ThreatDetection.
+			EmailAddresses
+		armTemplateParameters["threatDetectionEmailAccountAdmins"] = pp.
+			ThreatDetection.EmailAccountAdmins
+		armTemplateParameters["threatDetectionDisabledAlerts"] = pp.ThreatDetection.
+			DisabledAlerts
+		armTemplateParameters["threatDetectionRetentionDays"] = pp.ThreatDetection.
+			RetentionDays
+	}
+
+	if _, err := s.armDeployer.Deploy(
+		pc.ARMDeploymentName+"-audit",
+		resourceGroup,
+		location,
+		armTemplateAuditingAndThreatDetectionBytes,
+		nil, // Go template params
+		armTemplateParameters,
+		instance.StandardProvisioningContext.Tags,
+	); err != nil {
+		return nil, fmt.Errorf(
+			"error configuring auditing and threat detection: %s",
+			err,
+		)
+	}
+
+	return pc, nil
+}
@@ -0,0 +1,66 @@
+package sqldb
+
+import (
+	"testing"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAuditing(t *testing.T) {
+	cases := []struct {
+		name      string
+		auditing  Auditing
+		expectErr bool
+	}{
+		{
+			name:      "disabled",
+			auditing:  Auditing{Enabled: false},
+			expectErr: false,
+		},
+		{
+			name: "enabled with storageEndpoint and access key",
+			auditing: Auditing{
+				Enabled:                 true,
+				StorageEndpoint:         "https://example.blob.core.windows.net",
+				StorageAccountAccessKey: "key",
+			},
+			expectErr: false,
+		},
+		{
+			name: "enabled with useServerIdentity",
+			auditing: Auditing{
+				Enabled:           true,
+				UseServerIdentity: true,
+			},
+			expectErr: false,
+		},
+		{
+			name: "enabled with neither storageEndpoint nor useServerIdentity",
+			auditing: Auditing{
+				Enabled: true,
+			},
+			expectErr: true,
+		},
+		{
+			name: "enabled with storageEndpoint but no access key or server identity",
+			auditing: Auditing{
+				Enabled:         true,
+				StorageEndpoint: "https://example.blob.core.windows.net",
+			},
+			expectErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateAuditing(c.auditing)
+			if c.expectErr {
+				assert.NotNil(t, err)
+				_, ok := err.(*service.ValidationError)
+				assert.True(t, ok)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
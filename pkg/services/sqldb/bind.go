@@ -0,0 +1,264 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	az "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/open-service-broker-azure/pkg/azure"
+	"github.com/Azure/open-service-broker-azure/pkg/generate"
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+	mssql "github.com/denisenkom/go-mssqldb"
+	uuid "github.com/satori/go.uuid"
+)
+
+func (s *serviceManager) Bind(
+	instance service.Instance,
+	_ service.BindingParameters,
+) (service.BindingContext, service.Credentials, error) {
+	pc, ok := instance.ProvisioningContext.(*mssqlProvisioningContext)
+	if !ok {
+		return nil, nil, errors.New(
+			"error casting instance.ProvisioningContext as *mssqlProvisioningContext",
+		)
+	}
+
+	if pc.ActiveDirectoryAdminLogin != "" {
+		return s.bindAAD(pc)
+	}
+	return s.bindSQL(pc)
+}
+
+func (s *serviceManager) bindSQL(
+	pc *mssqlProvisioningContext,
+) (service.BindingContext, service.Credentials, error) {
+	loginName := generate.NewIdentifier()
+	password := generate.NewPassword()
+
+	db, err := s.sqlDatabaseDSNConnect(pc)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close() // nolint: errcheck
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error starting transaction: %s", err)
+	}
+	if _, err := tx.Exec(
+		fmt.Sprintf(
+			"CREATE LOGIN [%s] WITH PASSWORD='%s'",
+			loginName,
+			password,
+		),
+	); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return nil, nil, fmt.Errorf("error creating login: %s", err)
+	}
+	if _, err := tx.Exec(
+		fmt.Sprintf("CREATE USER [%s] FOR LOGIN [%s]", loginName, loginName),
+	); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return nil, nil, fmt.Errorf("error creating user: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("error committing transaction: %s", err)
+	}
+
+	bc := &mssqlBindingContext{
+		LoginName: loginName,
+	}
+	cred := &Credentials{
+		Host:           pc.FullyQualifiedDomainName,
+		Port:           1433,
+		Database:       pc.DatabaseName,
+		Username:       loginName,
+		Password:       password,
+		Authentication: "sql",
+	}
+	return bc, cred, nil
+}
+
+func (s *serviceManager) bindAAD(
+	pc *mssqlProvisioningContext,
+) (service.BindingContext, service.Credentials, error) {
+	aadUserName := fmt.Sprintf("broker-user-%s", uuid.NewV4().String())
+
+	// An AAD-only existing server has no SQL administrator login/password
+	// for sqlDatabaseDSNConnect to use, so the broker must connect as
+	// itself using an Azure AD access token instead.
+	db, err := s.sqlDatabaseAADTokenConnect(pc)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close() // nolint: errcheck
+
+	if _, err := db.Exec(
+		fmt.Sprintf(
+			"CREATE USER [%s] FROM EXTERNAL PROVIDER",
+			aadUserName,
+		),
+	); err != nil {
+		return nil, nil, fmt.Errorf(
+			"error creating Azure AD contained user: %s",
+			err,
+		)
+	}
+
+	authorityURL, err := aadAuthorityURL(pc.ActiveDirectoryTenantID)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"error determining Azure AD authority URL: %s",
+			err,
+		)
+	}
+
+	bc := &mssqlBindingContext{
+		AADUserName: aadUserName,
+	}
+	cred := &Credentials{
+		Host:           pc.FullyQualifiedDomainName,
+		Port:           1433,
+		Database:       pc.DatabaseName,
+		Username:       aadUserName,
+		Authentication: "aad-token",
+		TenantID:       pc.ActiveDirectoryTenantID,
+		AuthorityURL:   authorityURL,
+	}
+	return bc, cred, nil
+}
+
+// sqlDatabaseDSNConnect opens a connection to the database using the
+// server's administrator credentials so binding can create a new SQL
+// login and database user.
+func (s *serviceManager) sqlDatabaseDSNConnect(
+	pc *mssqlProvisioningContext,
+) (*sql.DB, error) {
+	dsn := fmt.Sprintf(
+		"server=%s;user id=%s;password=%s;port=1433;database=%s",
+		pc.FullyQualifiedDomainName,
+		pc.AdministratorLogin,
+		pc.AdministratorLoginPassword,
+		pc.DatabaseName,
+	)
+	db, err := sql.Open("mssql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %s", err)
+	}
+	return db, nil
+}
+
+// sqlDatabaseAADTokenConnect opens a connection to the database as the
+// broker's own service principal, authenticating with an Azure AD access
+// token rather than a SQL login. This is the only connection path that
+// works against an AAD-only server, where no SQL administrator
+// login/password exists.
+func (s *serviceManager) sqlDatabaseAADTokenConnect(
+	pc *mssqlProvisioningContext,
+) (*sql.DB, error) {
+	azureConfig, err := azure.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving Azure config: %s", err)
+	}
+	azureEnvironment, err := az.EnvironmentFromName(azureConfig.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving Azure environment: %s", err)
+	}
+	clientConfig := auth.NewClientCredentialsConfig(
+		azureConfig.ClientID,
+		azureConfig.ClientSecret,
+		azureConfig.TenantID,
+	)
+	clientConfig.Resource =
+		"https://" + azureEnvironment.SQLDatabaseDNSSuffix + "/"
+	clientConfig.AADEndpoint = azureEnvironment.ActiveDirectoryEndpoint
+	spt, err := clientConfig.ServicePrincipalToken()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error acquiring Azure AD token for the SQL Database resource: %s",
+			err,
+		)
+	}
+	if err := spt.EnsureFresh(); err != nil {
+		return nil, fmt.Errorf("error refreshing Azure AD token: %s", err)
+	}
+
+	dsn := fmt.Sprintf(
+		"server=%s;port=1433;database=%s",
+		pc.FullyQualifiedDomainName,
+		pc.DatabaseName,
+	)
+	connector, err := mssql.NewAccessTokenConnector(
+		dsn,
+		func() (string, error) {
+			if err := spt.EnsureFresh(); err != nil {
+				return "", err
+			}
+			return spt.OAuthToken(), nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error creating Azure AD token connector: %s",
+			err,
+		)
+	}
+	return sql.OpenDB(connector), nil
+}
+
+func (s *serviceManager) Unbind(
+	instance service.Instance,
+	bindingContext service.BindingContext,
+) error {
+	pc, ok := instance.ProvisioningContext.(*mssqlProvisioningContext)
+	if !ok {
+		return errors.New(
+			"error casting instance.ProvisioningContext as *mssqlProvisioningContext",
+		)
+	}
+	bc, ok := bindingContext.(*mssqlBindingContext)
+	if !ok {
+		return errors.New(
+			"error casting bindingContext as *mssqlBindingContext",
+		)
+	}
+
+	// An AAD contained user was created over an Azure AD token-based
+	// connection (see bindAAD), since an AAD-only server has no SQL
+	// administrator login/password for sqlDatabaseDSNConnect to use.
+	// Dropping it must go through the same connection path.
+	var db *sql.DB
+	var err error
+	if bc.AADUserName != "" {
+		db, err = s.sqlDatabaseAADTokenConnect(pc)
+	} else {
+		db, err = s.sqlDatabaseDSNConnect(pc)
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close() // nolint: errcheck
+
+	if bc.AADUserName != "" {
+		if _, err := db.Exec(
+			fmt.Sprintf("DROP USER [%s]", bc.AADUserName),
+		); err != nil {
+			return fmt.Errorf("error dropping Azure AD contained user: %s", err)
+		}
+		return nil
+	}
+
+	if _, err := db.Exec(
+		fmt.Sprintf("DROP USER [%s]", bc.LoginName),
+	); err != nil {
+		return fmt.Errorf("error dropping user: %s", err)
+	}
+	if _, err := db.Exec(
+		fmt.Sprintf("DROP LOGIN [%s]", bc.LoginName),
+	); err != nil {
+		return fmt.Errorf("error dropping login: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+)
+
+// enableTDE configures transparent data encryption for the newly
+// provisioned database. When the caller asked for service-managed
+// encryption (the default), it simply enables the database's own
+// transparentDataEncryption child resource. When the caller asked for
+// customer-managed encryption, it grants the server's managed identity
+// access to the specified Key Vault key and sets that key as the
+// server's encryption protector.
+func (s *serviceManager) enableTDE(
+	_ context.Context,
+	instance service.Instance,
+	_ service.Plan,
+) (service.ProvisioningContext, error) {
+	pc, ok := instance.ProvisioningContext.(*mssqlProvisioningContext)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.ProvisioningContext as *mssqlProvisioningContext",
+		)
+	}
+	pp, ok := instance.ProvisioningParameters.(*ProvisioningParameters)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.ProvisioningParameters as " +
+				"*mssql.ProvisioningParameters",
+		)
+	}
+
+	resourceGroup := instance.StandardProvisioningContext.ResourceGroup
+	location := instance.StandardProvisioningContext.Location
+	if !pc.IsNewServer {
+		server, ok := s.mssqlConfig.Servers[pc.ServerName]
+		if !ok {
+			return nil, fmt.Errorf(
+				`can't find serverName "%s" in Azure SQL Server configuration`,
+				pc.ServerName,
+			)
+		}
+		resourceGroup = server.ResourceGroupName
+		location = server.Location
+	}
+
+	if pp.Encryption.Mode != "customer-managed" {
+		if _, err := s.armDeployer.Deploy(
+			pc.ARMDeploymentName+"-tde",
+			resourceGroup,
+			location,
+			armTemplateServiceManagedTDEBytes,
+			nil, // Go template params
+			map[string]interface{}{
+				"serverName":   pc.ServerName,
+				"databaseName": pc.DatabaseName,
+			},
+			instance.StandardProvisioningContext.Tags,
+		); err != nil {
+			return nil, fmt.Errorf("error enabling transparent data encryption: %s", err)
+		}
+		return pc, nil
+	}
+
+	// validateEncryption rejects "customer-managed" for the existing-server
+	// scenario, since encryptionProtector and the managed identity it
+	// relies on are server-wide and an existing server is shared by many
+	// service instances.
+	outputs, err := s.armDeployer.Deploy(
+		pc.ARMDeploymentName+"-tde",
+		resourceGroup,
+		location,
+		armTemplateCustomerManagedTDEBytes,
+		nil, // Go template params
+		map[string]interface{}{
+			"serverName":  pc.ServerName,
+			"keyVaultURI": pp.Encryption.KeyVaultURI,
+			"keyName":     pp.Encryption.KeyName,
+			"keyVersion":  pp.Encryption.KeyVersion,
+		},
+		instance.StandardProvisioningContext.Tags,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error enabling customer-managed transparent data encryption: %s",
+			err,
+		)
+	}
+	principalID, ok := outputs["serverIdentityPrincipalId"].(string)
+	if !ok {
+		return nil, errors.New(
+			"error retrieving server identity principal id from deployment",
+		)
+	}
+	keyURI, ok := outputs["encryptionKeyURI"].(string)
+	if !ok {
+		return nil, errors.New(
+			"error retrieving encryption key uri from deployment",
+		)
+	}
+	pc.ServerIdentityPrincipalID = principalID
+	pc.EncryptionKeyURI = keyURI
+
+	return pc, nil
+}
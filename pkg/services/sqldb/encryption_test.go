@@ -0,0 +1,91 @@
+package sqldb
+
+import (
+	"testing"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEncryption(t *testing.T) {
+	cases := []struct {
+		name      string
+		pp        *ProvisioningParameters
+		expectErr bool
+	}{
+		{
+			name:      "mode unset",
+			pp:        &ProvisioningParameters{},
+			expectErr: false,
+		},
+		{
+			name: "service-managed",
+			pp: &ProvisioningParameters{
+				Encryption: Encryption{Mode: "service-managed"},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid mode",
+			pp: &ProvisioningParameters{
+				Encryption: Encryption{Mode: "not-a-mode"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "customer-managed on a new server",
+			pp: &ProvisioningParameters{
+				Encryption: Encryption{
+					Mode:        "customer-managed",
+					KeyVaultURI: "https://example.vault.azure.net",
+					KeyName:     "key1",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "customer-managed missing keyVaultURI",
+			pp: &ProvisioningParameters{
+				Encryption: Encryption{
+					Mode:    "customer-managed",
+					KeyName: "key1",
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "customer-managed missing keyName",
+			pp: &ProvisioningParameters{
+				Encryption: Encryption{
+					Mode:        "customer-managed",
+					KeyVaultURI: "https://example.vault.azure.net",
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "customer-managed on an existing, shared server",
+			pp: &ProvisioningParameters{
+				ServerName: "existing-server",
+				Encryption: Encryption{
+					Mode:        "customer-managed",
+					KeyVaultURI: "https://example.vault.azure.net",
+					KeyName:     "key1",
+				},
+			},
+			expectErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateEncryption(c.pp)
+			if c.expectErr {
+				assert.NotNil(t, err)
+				_, ok := err.(*service.ValidationError)
+				assert.True(t, ok)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
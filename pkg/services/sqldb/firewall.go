@@ -0,0 +1,138 @@
+package sqldb
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+)
+
+// allowAzureServicesRuleName is the conventional name Azure assigns a
+// firewall rule spanning 0.0.0.0-0.0.0.0, which permits other Azure
+// services to reach the server.
+const allowAzureServicesRuleName = "AllowAllWindowsAzureIps"
+
+func validateFirewallIPRange(
+	startField string,
+	endField string,
+	startIPStr string,
+	endIPStr string,
+) error {
+	startIP := net.ParseIP(startIPStr)
+	if startIPStr != "" && startIP == nil {
+		return service.NewValidationError(
+			startField,
+			fmt.Sprintf(`invalid value: "%s"`, startIPStr),
+		)
+	}
+	endIP := net.ParseIP(endIPStr)
+	if endIPStr != "" && endIP == nil {
+		return service.NewValidationError(
+			endField,
+			fmt.Sprintf(`invalid value: "%s"`, endIPStr),
+		)
+	}
+	if startIP == nil || endIP == nil {
+		return nil
+	}
+	//The net.IP.To4 method returns a 4 byte representation of an IPv4 address.
+	//Once converted,comparing two IP addresses can be done by using the
+	//bytes. Compare function. Per the ARM template documentation,
+	//startIP must be <= endIP.
+	if bytes.Compare(startIP.To4(), endIP.To4()) > 0 {
+		return service.NewValidationError(
+			endField,
+			fmt.Sprintf(
+				`invalid value: "%s". must be greater than or equal to %s`,
+				endIPStr,
+				startField,
+			),
+		)
+	}
+	return nil
+}
+
+func validateFirewallRules(rules []FirewallRule) error {
+	names := map[string]bool{}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return service.NewValidationError(
+				fmt.Sprintf("firewallRules[%d].name", i),
+				"must not be empty",
+			)
+		}
+		if names[rule.Name] {
+			return service.NewValidationError(
+				fmt.Sprintf("firewallRules[%d].name", i),
+				fmt.Sprintf(`duplicate firewall rule name: "%s"`, rule.Name),
+			)
+		}
+		names[rule.Name] = true
+		if rule.StartIP == "" {
+			return service.NewValidationError(
+				fmt.Sprintf("firewallRules[%d].startIPAddress", i),
+				"must not be empty",
+			)
+		}
+		if rule.EndIP == "" {
+			return service.NewValidationError(
+				fmt.Sprintf("firewallRules[%d].endIPAddress", i),
+				"must not be empty",
+			)
+		}
+		if err := validateFirewallIPRange(
+			fmt.Sprintf("firewallRules[%d].startIPAddress", i),
+			fmt.Sprintf("firewallRules[%d].endIPAddress", i),
+			rule.StartIP,
+			rule.EndIP,
+		); err != nil {
+			return err
+		}
+	}
+	for i, a := range rules {
+		startA := net.ParseIP(a.StartIP).To4()
+		endA := net.ParseIP(a.EndIP).To4()
+		for j := i + 1; j < len(rules); j++ {
+			b := rules[j]
+			startB := net.ParseIP(b.StartIP).To4()
+			endB := net.ParseIP(b.EndIP).To4()
+			if bytes.Compare(startA, endB) <= 0 && bytes.Compare(startB, endA) <= 0 {
+				return service.NewValidationError(
+					fmt.Sprintf("firewallRules[%d]", j),
+					fmt.Sprintf(
+						`range overlaps with firewall rule "%s"`,
+						a.Name,
+					),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// effectiveFirewallRules translates the legacy scalar firewall parameters
+// and the AllowAzureServices flag into the FirewallRules list, preferring
+// FirewallRules when both are supplied.
+func effectiveFirewallRules(
+	pp *ProvisioningParameters,
+) []FirewallRule {
+	rules := pp.FirewallRules
+	if len(rules) == 0 && pp.FirewallIPStart != "" && pp.FirewallIPEnd != "" {
+		rules = []FirewallRule{
+			{
+				Name:    "ClientIPAddress",
+				StartIP: pp.FirewallIPStart,
+				EndIP:   pp.FirewallIPEnd,
+			},
+		}
+	}
+	if pp.AllowAzureServices {
+		rules = append(rules, FirewallRule{
+			Name:    allowAzureServicesRuleName,
+			StartIP: "0.0.0.0",
+			EndIP:   "0.0.0.0",
+		})
+	}
+	return rules
+}
@@ -0,0 +1,144 @@
+package sqldb
+
+import (
+	"testing"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFirewallRules(t *testing.T) {
+	cases := []struct {
+		name      string
+		rules     []FirewallRule
+		expectErr bool
+	}{
+		{
+			name:      "no rules",
+			rules:     nil,
+			expectErr: false,
+		},
+		{
+			name: "valid rule",
+			rules: []FirewallRule{
+				{Name: "rule1", StartIP: "10.0.0.1", EndIP: "10.0.0.100"},
+			},
+			expectErr: false,
+		},
+		{
+			name: "missing name",
+			rules: []FirewallRule{
+				{StartIP: "10.0.0.1", EndIP: "10.0.0.100"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "duplicate name",
+			rules: []FirewallRule{
+				{Name: "rule1", StartIP: "10.0.0.1", EndIP: "10.0.0.100"},
+				{Name: "rule1", StartIP: "10.0.1.1", EndIP: "10.0.1.100"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "missing startIPAddress",
+			rules: []FirewallRule{
+				{Name: "rule1", EndIP: "10.0.0.100"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "missing endIPAddress",
+			rules: []FirewallRule{
+				{Name: "rule1", StartIP: "10.0.0.1"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "startIPAddress after endIPAddress",
+			rules: []FirewallRule{
+				{Name: "rule1", StartIP: "10.0.0.100", EndIP: "10.0.0.1"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "overlapping rules",
+			rules: []FirewallRule{
+				{Name: "rule1", StartIP: "10.0.0.1", EndIP: "10.0.0.100"},
+				{Name: "rule2", StartIP: "10.0.0.50", EndIP: "10.0.0.150"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "non-overlapping rules",
+			rules: []FirewallRule{
+				{Name: "rule1", StartIP: "10.0.0.1", EndIP: "10.0.0.100"},
+				{Name: "rule2", StartIP: "10.0.0.101", EndIP: "10.0.0.150"},
+			},
+			expectErr: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateFirewallRules(c.rules)
+			if c.expectErr {
+				assert.NotNil(t, err)
+				_, ok := err.(*service.ValidationError)
+				assert.True(t, ok)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestEffectiveFirewallRules(t *testing.T) {
+	cases := []struct {
+		name     string
+		pp       *ProvisioningParameters
+		expected []FirewallRule
+	}{
+		{
+			name:     "nothing set",
+			pp:       &ProvisioningParameters{},
+			expected: nil,
+		},
+		{
+			name: "legacy scalar fields translated",
+			pp: &ProvisioningParameters{
+				FirewallIPStart: "10.0.0.1",
+				FirewallIPEnd:   "10.0.0.100",
+			},
+			expected: []FirewallRule{
+				{Name: "ClientIPAddress", StartIP: "10.0.0.1", EndIP: "10.0.0.100"},
+			},
+		},
+		{
+			name: "firewallRules preferred over legacy scalar fields",
+			pp: &ProvisioningParameters{
+				FirewallIPStart: "10.0.0.1",
+				FirewallIPEnd:   "10.0.0.100",
+				FirewallRules: []FirewallRule{
+					{Name: "rule1", StartIP: "10.0.1.1", EndIP: "10.0.1.100"},
+				},
+			},
+			expected: []FirewallRule{
+				{Name: "rule1", StartIP: "10.0.1.1", EndIP: "10.0.1.100"},
+			},
+		},
+		{
+			name: "allowAzureServices appends the well-known rule",
+			pp: &ProvisioningParameters{
+				AllowAzureServices: true,
+			},
+			expected: []FirewallRule{
+				{Name: allowAzureServicesRuleName, StartIP: "0.0.0.0", EndIP: "0.0.0.0"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, effectiveFirewallRules(c.pp))
+		})
+	}
+}
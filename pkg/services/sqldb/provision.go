@@ -1,11 +1,10 @@
 package sqldb
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"net/url"
 
 	az "github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/open-service-broker-azure/pkg/azure"
@@ -49,42 +48,208 @@ func (s *serviceManager) ValidateProvisioningParameters(
 			)
 		}
 	}
-	startIP := net.ParseIP(pp.FirewallIPStart)
-	if pp.FirewallIPStart != "" && startIP == nil {
+	if err := validateFirewallIPRange(
+		"firewallStartIPAddress",
+		"firewallEndIPAddress",
+		pp.FirewallIPStart,
+		pp.FirewallIPEnd,
+	); err != nil {
+		return err
+	}
+	if err := validateFirewallRules(pp.FirewallRules); err != nil {
+		return err
+	}
+	if err := validateEncryption(pp); err != nil {
+		return err
+	}
+	if err := s.validateActiveDirectoryAdmin(pp); err != nil {
+		return err
+	}
+	if err := s.validateSource(pp); err != nil {
+		return err
+	}
+	if err := validateAuditing(pp.Auditing); err != nil {
+		return err
+	}
+	return s.validateRestore(pp)
+}
+
+func validateAuditing(auditing Auditing) error {
+	if !auditing.Enabled {
+		return nil
+	}
+	if auditing.StorageEndpoint == "" && !auditing.UseServerIdentity {
+		return service.NewValidationError(
+			"auditing.storageEndpoint",
+			"must be set, or auditing.useServerIdentity must be true, when "+
+				"auditing.enabled is true",
+		)
+	}
+	if auditing.StorageEndpoint != "" &&
+		auditing.StorageAccountAccessKey == "" &&
+		!auditing.UseServerIdentity {
+		return service.NewValidationError(
+			"auditing.storageAccountAccessKey",
+			"must be set when auditing.storageEndpoint is set and "+
+				"auditing.useServerIdentity is false",
+		)
+	}
+	return nil
+}
+
+// validateSource validates pp.Source. The edition/size check requested
+// alongside this validation can't be performed here: the broker doesn't
+// learn which plan (and therefore which edition/maxSizeBytes) the caller
+// selected until later steps such as importBacpac, which receive a
+// service.Plan. See importBacpac's size check for that half of the
+// validation.
+func (s *serviceManager) validateSource(pp *ProvisioningParameters) error {
+	source := pp.Source
+	if source == nil {
+		return nil
+	}
+	switch source.Type {
+	case "bacpac":
+		uri, err := url.Parse(source.StorageURI)
+		if err != nil || (uri.Scheme != "http" && uri.Scheme != "https") {
+			return service.NewValidationError(
+				"source.storageUri",
+				fmt.Sprintf(`invalid value: "%s"`, source.StorageURI),
+			)
+		}
+		switch source.StorageKeyType {
+		case "StorageAccessKey", "SharedAccessKey":
+		default:
+			return service.NewValidationError(
+				"source.storageKeyType",
+				fmt.Sprintf(`invalid value: "%s"`, source.StorageKeyType),
+			)
+		}
+		if source.StorageKey == "" {
+			return service.NewValidationError(
+				"source.storageKey",
+				"must not be empty",
+			)
+		}
+		// A BACPAC import requires SQL administrator credentials (see
+		// armTemplateImportBacpacBytes). A new server always generates
+		// one regardless of ActiveDirectoryAdmin, so only an existing,
+		// AAD-only server (no SQL administrator configured) is actually
+		// incompatible with this feature.
+		if pp.ServerName != "" {
+			server, ok := s.mssqlConfig.Servers[pp.ServerName]
+			if ok && server.AdministratorLogin == "" {
+				return service.NewValidationError(
+					"source.type",
+					fmt.Sprintf(
+						`bacpac import requires SQL administrator credentials, but `+
+							`server "%s" has none configured`,
+						pp.ServerName,
+					),
+				)
+			}
+		}
+		return nil
+	default:
+		return service.NewValidationError(
+			"source.type",
+			fmt.Sprintf(`invalid value: "%s"`, source.Type),
+		)
+	}
+}
+
+func (s *serviceManager) validateActiveDirectoryAdmin(
+	pp *ProvisioningParameters,
+) error {
+	if pp.ActiveDirectoryAdmin == nil {
+		if pp.ServerName != "" {
+			server := s.mssqlConfig.Servers[pp.ServerName]
+			if server.AdministratorLogin == "" && server.ActiveDirectoryAdmin == nil {
+				return service.NewValidationError(
+					"serverName",
+					fmt.Sprintf(
+						`server "%s" has neither a SQL administrator nor an Azure AD `+
+							`administrator configured`,
+						pp.ServerName,
+					),
+				)
+			}
+		}
+		return nil
+	}
+	aad := pp.ActiveDirectoryAdmin
+	if aad.Login == "" {
 		return service.NewValidationError(
-			"firewallStartIPAddress",
-			fmt.Sprintf(`invalid value: "%s"`, pp.FirewallIPStart),
+			"activeDirectoryAdmin.login",
+			"must not be empty",
 		)
 	}
-	endIP := net.ParseIP(pp.FirewallIPEnd)
-	if pp.FirewallIPEnd != "" && endIP == nil {
+	if aad.ObjectID == "" {
 		return service.NewValidationError(
-			"firewallEndIPAddress",
-			fmt.Sprintf(`invalid value: "%s"`, pp.FirewallIPEnd),
+			"activeDirectoryAdmin.objectId",
+			"must not be empty",
 		)
 	}
-	//The net.IP.To4 method returns a 4 byte representation of an IPv4 address.
-	//Once converted,comparing two IP addresses can be done by using the
-	//bytes. Compare function. Per the ARM template documentation,
-	//startIP must be <= endIP.
-	startBytes := startIP.To4()
-	endBytes := endIP.To4()
-	if bytes.Compare(startBytes, endBytes) > 0 {
+	if aad.TenantID == "" {
 		return service.NewValidationError(
-			"firewallEndIPAddress",
-			fmt.Sprintf(`invalid value: "%s". must be 
-				greater than or equal to firewallStartIPAddress`, pp.FirewallIPEnd),
+			"activeDirectoryAdmin.tenantId",
+			"must not be empty",
 		)
 	}
 	return nil
 }
 
+func validateEncryption(pp *ProvisioningParameters) error {
+	encryption := pp.Encryption
+	switch encryption.Mode {
+	case "", "service-managed":
+		return nil
+	case "customer-managed":
+		// encryptionProtector and the managed identity it relies on are
+		// server-wide settings. An "existing server" is shared by many
+		// service instances, so honoring this here would silently change
+		// the TDE key for every other database already on that server.
+		if pp.ServerName != "" {
+			return service.NewValidationError(
+				"encryption.mode",
+				"\"customer-managed\" is only supported when provisioning a new "+
+					"server, not an existing, shared server",
+			)
+		}
+		if encryption.KeyVaultURI == "" {
+			return service.NewValidationError(
+				"encryption.keyVaultURI",
+				"must be set when encryption.mode is \"customer-managed\"",
+			)
+		}
+		if encryption.KeyName == "" {
+			return service.NewValidationError(
+				"encryption.keyName",
+				"must be set when encryption.mode is \"customer-managed\"",
+			)
+		}
+		return nil
+	default:
+		return service.NewValidationError(
+			"encryption.mode",
+			fmt.Sprintf(`invalid value: "%s"`, encryption.Mode),
+		)
+	}
+}
+
 func (s *serviceManager) GetProvisioner(
 	service.Plan,
 ) (service.Provisioner, error) {
 	return service.NewProvisioner(
 		service.NewProvisioningStep("preProvision", s.preProvision),
 		service.NewProvisioningStep("deployARMTemplate", s.deployARMTemplate),
+		service.NewProvisioningStep("enableTDE", s.enableTDE),
+		service.NewProvisioningStep("aadAdmin", s.aadAdmin),
+		service.NewProvisioningStep("importBacpac", s.importBacpac),
+		service.NewProvisioningStep(
+			"configureAuditingAndThreatDetection",
+			s.configureAuditingAndThreatDetection,
+		),
 	)
 }
 
@@ -114,7 +279,11 @@ func (s *serviceManager) preProvision(
 		pc.IsNewServer = true
 		pc.AdministratorLogin = generate.NewIdentifier()
 		pc.AdministratorLoginPassword = generate.NewPassword()
-		pc.DatabaseName = generate.NewIdentifier()
+		pc.DatabaseName = databaseNameForProvisioning(pp)
+		if pp.ActiveDirectoryAdmin != nil {
+			pc.ActiveDirectoryAdminLogin = pp.ActiveDirectoryAdmin.Login
+			pc.ActiveDirectoryTenantID = pp.ActiveDirectoryAdmin.TenantID
+		}
 	} else {
 		// exisiting server scenario
 		servers := s.mssqlConfig.Servers
@@ -131,7 +300,7 @@ func (s *serviceManager) preProvision(
 		pc.IsNewServer = false
 		pc.AdministratorLogin = server.AdministratorLogin
 		pc.AdministratorLoginPassword = server.AdministratorLoginPassword
-		pc.DatabaseName = generate.NewIdentifier()
+		pc.DatabaseName = databaseNameForProvisioning(pp)
 
 		// Ensure the server configuration works
 		azureConfig, err := azure.GetConfig()
@@ -148,6 +317,10 @@ func (s *serviceManager) preProvision(
 			server.ServerName,
 			sqlDatabaseDNSSuffix,
 		)
+		if server.ActiveDirectoryAdmin != nil {
+			pc.ActiveDirectoryAdminLogin = server.ActiveDirectoryAdmin.Login
+			pc.ActiveDirectoryTenantID = server.ActiveDirectoryAdmin.TenantID
+		}
 	}
 	return pc, nil
 }
@@ -168,15 +341,7 @@ func buildARMTemplateParameters(
 		"maxSizeBytes": plan.GetProperties().
 			Extended["maxSizeBytes"],
 	}
-	//Only include these if they are not empty.
-	//ARM Deployer will fail if the values included are not
-	//valid IPV4 addresses (i.e. empty string wil fail)
-	if provisioningParameters.FirewallIPStart != "" {
-		p["firewallStartIpAddress"] = provisioningParameters.FirewallIPStart
-	}
-	if provisioningParameters.FirewallIPEnd != "" {
-		p["firewallEndIpAddress"] = provisioningParameters.FirewallIPEnd
-	}
+	p["firewallRules"] = effectiveFirewallRules(provisioningParameters)
 	return p
 }
 
@@ -198,14 +363,24 @@ func (s *serviceManager) deployARMTemplate(
 				"*mssql.ProvisioningParameters",
 		)
 	}
+	pc.Edition = plan.GetProperties().Extended["edition"].(string)
+	pc.RequestedServiceObjectiveName = plan.GetProperties().
+		Extended["requestedServiceObjectiveName"].(string)
+	pc.MaxSizeBytes = plan.GetProperties().Extended["maxSizeBytes"].(int64)
+
 	if pc.IsNewServer {
 		armTemplateParameters := buildARMTemplateParameters(plan, pc, pp)
+		databaseTemplate := armTemplateNewServerBytes
+		if pp.Restore != nil {
+			addRestoreARMTemplateParameters(armTemplateParameters, pp.Restore)
+			databaseTemplate = armTemplateNewServerRestoreBytes
+		}
 		// new server scenario
 		outputs, err := s.armDeployer.Deploy(
 			pc.ARMDeploymentName,
 			instance.StandardProvisioningContext.ResourceGroup,
 			instance.StandardProvisioningContext.Location,
-			armTemplateNewServerBytes,
+			databaseTemplate,
 			nil, // Go template params
 			armTemplateParameters,
 			instance.StandardProvisioningContext.Tags,
@@ -232,21 +407,29 @@ func (s *serviceManager) deployARMTemplate(
 			)
 		}
 
+		existingServerParameters := map[string]interface{}{ // ARM template params
+			"serverName":   pc.ServerName,
+			"databaseName": pc.DatabaseName,
+			"edition":      plan.GetProperties().Extended["edition"],
+			"requestedServiceObjectiveName": plan.GetProperties().
+				Extended["requestedServiceObjectiveName"],
+			"maxSizeBytes": plan.GetProperties().
+				Extended["maxSizeBytes"],
+			"firewallRules": effectiveFirewallRules(pp),
+		}
+		databaseTemplate := armTemplateExistingServerBytes
+		if pp.Restore != nil {
+			addRestoreARMTemplateParameters(existingServerParameters, pp.Restore)
+			databaseTemplate = armTemplateExistingServerRestoreBytes
+		}
+
 		_, err := s.armDeployer.Deploy(
 			pc.ARMDeploymentName,
 			server.ResourceGroupName,
 			server.Location,
-			armTemplateExistingServerBytes,
+			databaseTemplate,
 			nil, // Go template params
-			map[string]interface{}{ // ARM template params
-				"serverName":   pc.ServerName,
-				"databaseName": pc.DatabaseName,
-				"edition":      plan.GetProperties().Extended["edition"],
-				"requestedServiceObjectiveName": plan.GetProperties().
-					Extended["requestedServiceObjectiveName"],
-				"maxSizeBytes": plan.GetProperties().
-					Extended["maxSizeBytes"],
-			},
+			existingServerParameters,
 			instance.StandardProvisioningContext.Tags,
 		)
 		if err != nil {
@@ -0,0 +1,76 @@
+package sqldb
+
+import (
+	"fmt"
+
+	"github.com/Azure/open-service-broker-azure/pkg/generate"
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+)
+
+// databaseNameForProvisioning returns the database name to provision
+// with. A restore may target a caller-chosen name; absent a restore, the
+// broker always generates one.
+func databaseNameForProvisioning(pp *ProvisioningParameters) string {
+	if pp.Restore != nil && pp.DatabaseName != "" {
+		return pp.DatabaseName
+	}
+	return generate.NewIdentifier()
+}
+
+func (s *serviceManager) validateRestore(pp *ProvisioningParameters) error {
+	if pp.Restore == nil {
+		return nil
+	}
+	restore := pp.Restore
+	switch restore.Mode {
+	// "point-in-time" is intentionally not accepted: validating a
+	// requested restore point against the source database's short-term
+	// retention window requires a restorableDroppedDatabases/
+	// recoverableDatabases lookup that the broker's ARM client doesn't
+	// yet expose, and accepting the mode without that check would risk
+	// a deployment that fails expensively, mid-restore, against Azure.
+	case "geo-restore", "long-term-retention":
+	default:
+		return service.NewValidationError(
+			"restore.mode",
+			fmt.Sprintf(`invalid value: "%s"`, restore.Mode),
+		)
+	}
+	if restore.SourceDatabaseID == "" {
+		return service.NewValidationError(
+			"restore.sourceDatabaseId",
+			"must not be empty",
+		)
+	}
+	// Restoring into an existing-server slot requires the broker to know
+	// the target server's admin credentials, which it only has for
+	// servers present in its own configuration.
+	if pp.ServerName != "" {
+		if _, ok := s.mssqlConfig.Servers[pp.ServerName]; !ok {
+			return service.NewValidationError(
+				"serverName",
+				fmt.Sprintf(
+					`can't restore into serverName "%s": its administrator `+
+						`credentials are not known to the broker`,
+					pp.ServerName,
+				),
+			)
+		}
+	}
+	return nil
+}
+
+func addRestoreARMTemplateParameters(
+	armTemplateParameters map[string]interface{},
+	restore *Restore,
+) {
+	var createMode string
+	switch restore.Mode {
+	case "geo-restore":
+		createMode = "Recovery"
+	case "long-term-retention":
+		createMode = "RestoreLongTermRetentionBackup"
+	}
+	armTemplateParameters["createMode"] = createMode
+	armTemplateParameters["sourceDatabaseId"] = restore.SourceDatabaseID
+}
@@ -0,0 +1,112 @@
+package sqldb
+
+import (
+	"testing"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRestore(t *testing.T) {
+	s := &serviceManager{
+		mssqlConfig: Config{
+			Servers: map[string]ServerConfig{
+				"known-server": {ServerName: "known-server"},
+			},
+		},
+	}
+	cases := []struct {
+		name      string
+		pp        *ProvisioningParameters
+		expectErr bool
+	}{
+		{
+			name:      "no restore",
+			pp:        &ProvisioningParameters{},
+			expectErr: false,
+		},
+		{
+			name: "valid geo-restore",
+			pp: &ProvisioningParameters{
+				Restore: &Restore{
+					Mode:             "geo-restore",
+					SourceDatabaseID: "source-db-id",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "valid long-term-retention",
+			pp: &ProvisioningParameters{
+				Restore: &Restore{
+					Mode:             "long-term-retention",
+					SourceDatabaseID: "source-db-id",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "point-in-time is not an accepted mode",
+			pp: &ProvisioningParameters{
+				Restore: &Restore{
+					Mode:             "point-in-time",
+					SourceDatabaseID: "source-db-id",
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid mode",
+			pp: &ProvisioningParameters{
+				Restore: &Restore{
+					Mode:             "not-a-mode",
+					SourceDatabaseID: "source-db-id",
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "missing sourceDatabaseId",
+			pp: &ProvisioningParameters{
+				Restore: &Restore{
+					Mode: "geo-restore",
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "unknown existing server",
+			pp: &ProvisioningParameters{
+				ServerName: "unknown-server",
+				Restore: &Restore{
+					Mode:             "geo-restore",
+					SourceDatabaseID: "source-db-id",
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "known existing server",
+			pp: &ProvisioningParameters{
+				ServerName: "known-server",
+				Restore: &Restore{
+					Mode:             "geo-restore",
+					SourceDatabaseID: "source-db-id",
+				},
+			},
+			expectErr: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := s.validateRestore(c.pp)
+			if c.expectErr {
+				assert.NotNil(t, err)
+				_, ok := err.(*service.ValidationError)
+				assert.True(t, ok)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -1,26 +1,121 @@
 package sqldb
 
-import "github.com/Azure/open-service-broker-azure/pkg/service"
+import (
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+)
 
 // ProvisioningParameters encapsulates MSSQL-specific provisioning options
 type ProvisioningParameters struct {
-	ServerName      string `json:"server"`
-	FirewallIPStart string `json:"firewallStartIPAddress"`
-	FirewallIPEnd   string `json:"firewallEndIPAddress"`
+	ServerName string `json:"server"`
+	// DatabaseName, when set, is used instead of a generated name. It is
+	// honored only when Restore is set, since a restore may target a
+	// specific, caller-chosen database name.
+	DatabaseName string `json:"database"`
+	// Deprecated: use FirewallRules instead. When set and FirewallRules is
+	// empty, this is translated into a single-entry FirewallRules list.
+	FirewallIPStart      string                `json:"firewallStartIPAddress"`
+	FirewallIPEnd        string                `json:"firewallEndIPAddress"`
+	FirewallRules        []FirewallRule        `json:"firewallRules"`
+	AllowAzureServices   bool                  `json:"allowAzureServices"`
+	Encryption           Encryption            `json:"encryption"`
+	ActiveDirectoryAdmin *ActiveDirectoryAdmin `json:"activeDirectoryAdmin"`
+	Source               *Source               `json:"source"`
+	Auditing             Auditing              `json:"auditing"`
+	ThreatDetection      ThreatDetection       `json:"threatDetection"`
+	Restore              *Restore              `json:"restore"`
+}
+
+// Restore describes a geo-restore or long-term-retention restore that
+// should be used to seed a new database instead of creating one from
+// scratch. "point-in-time" is not supported: validating a requested
+// restore point against the source database's retention window requires
+// a lookup the broker's ARM client doesn't yet expose.
+type Restore struct {
+	Mode             string `json:"mode"`
+	SourceDatabaseID string `json:"sourceDatabaseId"`
+}
+
+// Auditing encapsulates database-level auditing configuration. When
+// Enabled is true, either StorageEndpoint/StorageAccountAccessKey or
+// UseServerIdentity must be provided so the broker knows how to
+// authenticate to the audit log storage account.
+type Auditing struct {
+	Enabled                 bool     `json:"enabled"`
+	StorageEndpoint         string   `json:"storageEndpoint"`
+	StorageAccountAccessKey string   `json:"storageAccountAccessKey"`
+	UseServerIdentity       bool     `json:"useServerIdentity"`
+	RetentionDays           int      `json:"retentionDays"`
+	AuditActionsAndGroups   []string `json:"auditActionsAndGroups"`
+}
+
+// ThreatDetection encapsulates database-level Advanced Threat Protection
+// configuration.
+type ThreatDetection struct {
+	Enabled            bool     `json:"enabled"`
+	EmailAddresses     []string `json:"emailAddresses"`
+	EmailAccountAdmins bool     `json:"emailAccountAdmins"`
+	DisabledAlerts     []string `json:"disabledAlerts"`
+	RetentionDays      int      `json:"retentionDays"`
+}
+
+// Source describes an external data source used to seed a newly
+// provisioned database instead of creating it empty.
+type Source struct {
+	Type           string `json:"type"`
+	StorageURI     string `json:"storageUri"`
+	StorageKeyType string `json:"storageKeyType"`
+	StorageKey     string `json:"storageKey"`
+}
+
+// ActiveDirectoryAdmin identifies the Azure AD principal that should be
+// configured as the server-level administrator.
+type ActiveDirectoryAdmin struct {
+	Login    string `json:"login"`
+	ObjectID string `json:"objectId"`
+	TenantID string `json:"tenantId"`
+}
+
+// FirewallRule represents a single named server-level firewall rule,
+// expressed as an inclusive range of IPv4 addresses.
+type FirewallRule struct {
+	Name    string `json:"name"`
+	StartIP string `json:"startIPAddress"`
+	EndIP   string `json:"endIPAddress"`
+}
+
+// Encryption encapsulates the transparent data encryption options for a
+// database. When Mode is "customer-managed", KeyVaultURI, KeyName, and
+// KeyVersion identify the Key Vault key used to protect the database
+// encryption key.
+type Encryption struct {
+	Mode        string `json:"mode"`
+	KeyVaultURI string `json:"keyVaultURI"`
+	KeyName     string `json:"keyName"`
+	KeyVersion  string `json:"keyVersion"`
 }
 
 type mssqlProvisioningContext struct {
-	ARMDeploymentName          string `json:"armDeployment"`
-	ServerName                 string `json:"server"`
-	IsNewServer                bool   `json:"isNewServer"`
-	AdministratorLogin         string `json:"administratorLogin"`
-	AdministratorLoginPassword string `json:"administratorLoginPassword"`
-	DatabaseName               string `json:"database"`
-	FullyQualifiedDomainName   string `json:"fullyQualifiedDomainName"`
+	ARMDeploymentName             string `json:"armDeployment"`
+	ServerName                    string `json:"server"`
+	IsNewServer                   bool   `json:"isNewServer"`
+	AdministratorLogin            string `json:"administratorLogin"`
+	AdministratorLoginPassword    string `json:"administratorLoginPassword"`
+	DatabaseName                  string `json:"database"`
+	FullyQualifiedDomainName      string `json:"fullyQualifiedDomainName"`
+	Edition                       string `json:"edition"`
+	RequestedServiceObjectiveName string `json:"requestedServiceObjectiveName"`
+	MaxSizeBytes                  int64  `json:"maxSizeBytes"`
+	EncryptionKeyURI              string `json:"encryptionKeyURI"`
+	ServerIdentityPrincipalID     string `json:"serverIdentityPrincipalID"`
+	ActiveDirectoryAdminLogin     string `json:"activeDirectoryAdminLogin"`
+	ActiveDirectoryTenantID       string `json:"activeDirectoryTenantID"`
 }
 
 // UpdatingParameters encapsulates MSSQL-specific updating options
 type UpdatingParameters struct {
+	Edition                       string `json:"edition"`
+	RequestedServiceObjectiveName string `json:"requestedServiceObjectiveName"` // nolint: lll
+	MaxSizeBytes                  int64  `json:"maxSizeBytes"`
 }
 
 // BindingParameters encapsulates MSSQL-specific binding options
@@ -29,6 +124,9 @@ type BindingParameters struct {
 
 type mssqlBindingContext struct {
 	LoginName string `json:"loginName"`
+	// AADUserName is set instead of LoginName when the binding issued an
+	// Azure AD contained database user rather than a SQL login.
+	AADUserName string `json:"aadUserName"`
 }
 
 // Credentials encapsulates MSSQL-specific coonection details and credentials.
@@ -38,16 +136,23 @@ type Credentials struct {
 	Database string `json:"database"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Authentication is "sql" for a username/password login, or "aad-token"
+	// when the client is expected to acquire an Azure AD access token for
+	// the SQL Database resource and present it in place of a password.
+	Authentication string `json:"authentication"`
+	TenantID       string `json:"tenantId,omitempty"`
+	AuthorityURL   string `json:"authorityUrl,omitempty"`
 }
 
 // ServerConfig represents all configuration details needed for connecting to
 // an Azure SQL Server.
 type ServerConfig struct {
-	ServerName                 string `json:"serverName"`
-	ResourceGroupName          string `json:"resourceGroup"`
-	Location                   string `json:"location"`
-	AdministratorLogin         string `json:"administratorLogin"`
-	AdministratorLoginPassword string `json:"administratorLoginPassword"`
+	ServerName                 string                `json:"serverName"`
+	ResourceGroupName          string                `json:"resourceGroup"`
+	Location                   string                `json:"location"`
+	AdministratorLogin         string                `json:"administratorLogin"`
+	AdministratorLoginPassword string                `json:"administratorLoginPassword"`
+	ActiveDirectoryAdmin       *ActiveDirectoryAdmin `json:"activeDirectoryAdmin"`
 }
 
 // Config contains only a map of ServerConfig
@@ -0,0 +1,250 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+)
+
+// editionCapability describes the service objectives and size range that
+// Azure SQL Database allows for a given edition. These mirror what the
+// Azure SQL capabilities API exposes.
+type editionCapability struct {
+	AllowedServiceObjectives []string
+	MinSizeBytes             int64
+	MaxSizeBytes             int64
+}
+
+var editionCapabilities = map[string]editionCapability{
+	"Basic": {
+		AllowedServiceObjectives: []string{"Basic"},
+		MinSizeBytes:             104857600,  // 100 MB
+		MaxSizeBytes:             2147483648, // 2 GB
+	},
+	"Standard": {
+		AllowedServiceObjectives: []string{
+			"S0", "S1", "S2", "S3", "S4", "S6", "S7", "S9", "S12",
+		},
+		MinSizeBytes: 104857600,     // 100 MB
+		MaxSizeBytes: 1099511627776, // 1 TB
+	},
+	"Premium": {
+		AllowedServiceObjectives: []string{
+			"P1", "P2", "P4", "P6", "P11", "P15",
+		},
+		MinSizeBytes: 104857600,     // 100 MB
+		MaxSizeBytes: 4398046511104, // 4 TB
+	},
+	"GeneralPurpose": {
+		AllowedServiceObjectives: []string{
+			"GP_Gen4_1", "GP_Gen4_2", "GP_Gen4_4", "GP_Gen4_8", "GP_Gen4_16",
+			"GP_Gen4_24", "GP_Gen5_2", "GP_Gen5_4", "GP_Gen5_8", "GP_Gen5_16",
+			"GP_Gen5_24", "GP_Gen5_32", "GP_Gen5_40",
+		},
+		MinSizeBytes: 104857600,     // 100 MB
+		MaxSizeBytes: 4398046511104, // 4 TB
+	},
+	"BusinessCritical": {
+		AllowedServiceObjectives: []string{
+			"BC_Gen4_1", "BC_Gen4_2", "BC_Gen4_4", "BC_Gen4_8", "BC_Gen4_16",
+			"BC_Gen4_24", "BC_Gen5_2", "BC_Gen5_4", "BC_Gen5_8", "BC_Gen5_16",
+			"BC_Gen5_24", "BC_Gen5_32", "BC_Gen5_40",
+		},
+		MinSizeBytes: 104857600,     // 100 MB
+		MaxSizeBytes: 4398046511104, // 4 TB
+	},
+}
+
+func validateSku(
+	fieldPrefix string,
+	edition string,
+	requestedServiceObjectiveName string,
+	maxSizeBytes int64,
+) error {
+	capability, ok := editionCapabilities[edition]
+	if !ok {
+		return service.NewValidationError(
+			fieldPrefix+"edition",
+			fmt.Sprintf(`invalid value: "%s"`, edition),
+		)
+	}
+	objectiveIsAllowed := false
+	for _, allowed := range capability.AllowedServiceObjectives {
+		if allowed == requestedServiceObjectiveName {
+			objectiveIsAllowed = true
+			break
+		}
+	}
+	if !objectiveIsAllowed {
+		return service.NewValidationError(
+			fieldPrefix+"requestedServiceObjectiveName",
+			fmt.Sprintf(
+				`invalid value: "%s" is not a valid service objective for the `+
+					`"%s" edition`,
+				requestedServiceObjectiveName,
+				edition,
+			),
+		)
+	}
+	if maxSizeBytes < capability.MinSizeBytes ||
+		maxSizeBytes > capability.MaxSizeBytes {
+		return service.NewValidationError(
+			fieldPrefix+"maxSizeBytes",
+			fmt.Sprintf(
+				`invalid value: %d is outside the allowed range [%d, %d] for the `+
+					`"%s" edition`,
+				maxSizeBytes,
+				capability.MinSizeBytes,
+				capability.MaxSizeBytes,
+				edition,
+			),
+		)
+	}
+	return nil
+}
+
+// effectiveUpdatingParameters returns the edition, service objective, and
+// max size that an update should apply, falling back to the database's
+// currently provisioned values for any field the caller left unset. Both
+// ValidateUpdatingParameters and deployUpdatedARMTemplate call this
+// instead of one mutating up and the other depending on that mutation
+// having already happened.
+func effectiveUpdatingParameters(
+	up *UpdatingParameters,
+	pc *mssqlProvisioningContext,
+) (string, string, int64) {
+	edition := up.Edition
+	if edition == "" {
+		edition = pc.Edition
+	}
+	requestedServiceObjectiveName := up.RequestedServiceObjectiveName
+	if requestedServiceObjectiveName == "" {
+		requestedServiceObjectiveName = pc.RequestedServiceObjectiveName
+	}
+	maxSizeBytes := up.MaxSizeBytes
+	if maxSizeBytes == 0 {
+		maxSizeBytes = pc.MaxSizeBytes
+	}
+	return edition, requestedServiceObjectiveName, maxSizeBytes
+}
+
+func (s *serviceManager) ValidateUpdatingParameters(
+	instance service.Instance,
+) error {
+	up, ok := instance.UpdatingParameters.(*UpdatingParameters)
+	if !ok {
+		return errors.New(
+			"error casting instance.UpdatingParameters as " +
+				"*mssql.UpdatingParameters",
+		)
+	}
+	pc, ok := instance.ProvisioningContext.(*mssqlProvisioningContext)
+	if !ok {
+		return errors.New(
+			"error casting instance.ProvisioningContext as *mssqlProvisioningContext",
+		)
+	}
+	edition, requestedServiceObjectiveName, maxSizeBytes :=
+		effectiveUpdatingParameters(up, pc)
+	if err := validateSku(
+		"",
+		edition,
+		requestedServiceObjectiveName,
+		maxSizeBytes,
+	); err != nil {
+		return err
+	}
+	// pc.MaxSizeBytes reflects the size the database was provisioned or
+	// last resized to, not how much of it is actually in use, which the
+	// broker has no way to query. Shrinking below the provisioned size is
+	// still disallowed, since Azure SQL Database itself rejects it.
+	if maxSizeBytes < pc.MaxSizeBytes {
+		return service.NewValidationError(
+			"maxSizeBytes",
+			fmt.Sprintf(
+				`invalid value: %d would shrink the database below its current `+
+					`provisioned size of %d bytes, which Azure SQL Database does `+
+					`not allow regardless of how much of that space is actually `+
+					`in use`,
+				maxSizeBytes,
+				pc.MaxSizeBytes,
+			),
+		)
+	}
+	return nil
+}
+
+func (s *serviceManager) GetUpdater(
+	service.Plan,
+) (service.Updater, error) {
+	return service.NewUpdater(
+		service.NewUpdatingStep(
+			"deployUpdatedARMTemplate",
+			s.deployUpdatedARMTemplate,
+		),
+	)
+}
+
+func (s *serviceManager) deployUpdatedARMTemplate(
+	_ context.Context,
+	instance service.Instance,
+	_ service.Plan,
+) (service.ProvisioningContext, error) {
+	pc, ok := instance.ProvisioningContext.(*mssqlProvisioningContext)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.ProvisioningContext as *mssqlProvisioningContext",
+		)
+	}
+	up, ok := instance.UpdatingParameters.(*UpdatingParameters)
+	if !ok {
+		return nil, errors.New(
+			"error casting instance.UpdatingParameters as " +
+				"*mssql.UpdatingParameters",
+		)
+	}
+
+	edition, requestedServiceObjectiveName, maxSizeBytes :=
+		effectiveUpdatingParameters(up, pc)
+	armTemplateParameters := map[string]interface{}{
+		"serverName":                    pc.ServerName,
+		"databaseName":                  pc.DatabaseName,
+		"edition":                       edition,
+		"requestedServiceObjectiveName": requestedServiceObjectiveName,
+		"maxSizeBytes":                  maxSizeBytes,
+	}
+
+	resourceGroup := instance.StandardProvisioningContext.ResourceGroup
+	location := instance.StandardProvisioningContext.Location
+	if !pc.IsNewServer {
+		server, ok := s.mssqlConfig.Servers[pc.ServerName]
+		if !ok {
+			return nil, fmt.Errorf(
+				`can't find serverName "%s" in Azure SQL Server configuration`,
+				pc.ServerName,
+			)
+		}
+		resourceGroup = server.ResourceGroupName
+		location = server.Location
+	}
+
+	if _, err := s.armDeployer.Deploy(
+		pc.ARMDeploymentName,
+		resourceGroup,
+		location,
+		armTemplateUpdateDatabaseBytes,
+		nil, // Go template params
+		armTemplateParameters,
+		instance.StandardProvisioningContext.Tags,
+	); err != nil {
+		return nil, fmt.Errorf("error deploying ARM template: %s", err)
+	}
+
+	pc.Edition = edition
+	pc.RequestedServiceObjectiveName = requestedServiceObjectiveName
+	pc.MaxSizeBytes = maxSizeBytes
+
+	return pc, nil
+}
@@ -0,0 +1,129 @@
+package sqldb
+
+import (
+	"testing"
+
+	"github.com/Azure/open-service-broker-azure/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSku(t *testing.T) {
+	cases := []struct {
+		name                          string
+		edition                       string
+		requestedServiceObjectiveName string
+		maxSizeBytes                  int64
+		expectErr                     bool
+	}{
+		{
+			name:                          "valid Basic",
+			edition:                       "Basic",
+			requestedServiceObjectiveName: "Basic",
+			maxSizeBytes:                  1073741824, // 1 GB
+			expectErr:                     false,
+		},
+		{
+			name:                          "valid Standard",
+			edition:                       "Standard",
+			requestedServiceObjectiveName: "S1",
+			maxSizeBytes:                  1073741824, // 1 GB
+			expectErr:                     false,
+		},
+		{
+			name:                          "invalid edition",
+			edition:                       "NotAnEdition",
+			requestedServiceObjectiveName: "Basic",
+			maxSizeBytes:                  1073741824,
+			expectErr:                     true,
+		},
+		{
+			name:                          "service objective not valid for edition",
+			edition:                       "Basic",
+			requestedServiceObjectiveName: "S1",
+			maxSizeBytes:                  1073741824,
+			expectErr:                     true,
+		},
+		{
+			name:                          "maxSizeBytes below edition minimum",
+			edition:                       "Basic",
+			requestedServiceObjectiveName: "Basic",
+			maxSizeBytes:                  1,
+			expectErr:                     true,
+		},
+		{
+			name:                          "maxSizeBytes above edition maximum",
+			edition:                       "Basic",
+			requestedServiceObjectiveName: "Basic",
+			maxSizeBytes:                  4398046511104, // 4 TB
+			expectErr:                     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateSku(
+				"",
+				c.edition,
+				c.requestedServiceObjectiveName,
+				c.maxSizeBytes,
+			)
+			if c.expectErr {
+				assert.NotNil(t, err)
+				_, ok := err.(*service.ValidationError)
+				assert.True(t, ok)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestEffectiveUpdatingParameters(t *testing.T) {
+	pc := &mssqlProvisioningContext{
+		Edition:                       "Standard",
+		RequestedServiceObjectiveName: "S1",
+		MaxSizeBytes:                  1073741824,
+	}
+	cases := []struct {
+		name                 string
+		up                   *UpdatingParameters
+		expectedEdition      string
+		expectedObjective    string
+		expectedMaxSizeBytes int64
+	}{
+		{
+			name:                 "nothing set falls back to the provisioning context",
+			up:                   &UpdatingParameters{},
+			expectedEdition:      "Standard",
+			expectedObjective:    "S1",
+			expectedMaxSizeBytes: 1073741824,
+		},
+		{
+			name: "everything set overrides the provisioning context",
+			up: &UpdatingParameters{
+				Edition:                       "Premium",
+				RequestedServiceObjectiveName: "P1",
+				MaxSizeBytes:                  2147483648,
+			},
+			expectedEdition:      "Premium",
+			expectedObjective:    "P1",
+			expectedMaxSizeBytes: 2147483648,
+		},
+		{
+			name: "partially set only overrides what's set",
+			up: &UpdatingParameters{
+				MaxSizeBytes: 2147483648,
+			},
+			expectedEdition:      "Standard",
+			expectedObjective:    "S1",
+			expectedMaxSizeBytes: 2147483648,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			edition, objective, maxSizeBytes := effectiveUpdatingParameters(c.up, pc)
+			assert.Equal(t, c.expectedEdition, edition)
+			assert.Equal(t, c.expectedObjective, objective)
+			assert.Equal(t, c.expectedMaxSizeBytes, maxSizeBytes)
+		})
+	}
+}